@@ -5,28 +5,26 @@ import (
 	"database/sql"
 	"encoding/base64"
 	"encoding/json"
+	"fmt"
+	"log"
 	"net/http"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
+
+	"nrv.dev-webapp/pkg/auth"
 )
 
 const sessionDuration = 24 * time.Hour
 
-var sessions = make(map[string]session)
-
-type session struct {
-	username string
-	expiry   time.Time
-}
-
-func (s session) isExpired() bool {
-	return s.expiry.Before(time.Now())
-}
+// sessionStore backs signin/logout/checkAuth. It is initialized in main()
+// before the HTTP server starts listening.
+var sessionStore auth.SessionStore
 
 type Credentials struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
+	Email string `json:"email"`
 	InvitationCode string `json:"invitationCode"`
 }
 
@@ -48,6 +46,11 @@ func signup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if creds.Email == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
 	// Check if invitation code is valid
 	var used bool
 	err = db.QueryRow("SELECT used FROM invitation_codes WHERE code = ?", creds.InvitationCode).Scan(&used)
@@ -70,14 +73,14 @@ func signup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	stmt, err := db.Prepare("INSERT INTO users (username, password_hash) VALUES (?, ?)")
+	stmt, err := db.Prepare("INSERT INTO users (username, password_hash, email, verified) VALUES (?, ?, ?, 0)")
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 	defer stmt.Close()
 
-	_, err = stmt.Exec(creds.Username, hashedPassword)
+	_, err = stmt.Exec(creds.Username, hashedPassword, creds.Email)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
@@ -97,6 +100,16 @@ func signup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	verifyToken, err := tokenStore.Issue("verify", creds.Username, verificationTokenDuration)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	link := fmt.Sprintf("%s/api/verify?token=%s", publicBaseURL(), verifyToken)
+	if err := mailer.SendVerification(creds.Email, link); err != nil {
+		log.Printf("mail: failed to send verification email: %v", err)
+	}
+
 	w.WriteHeader(http.StatusCreated)
 }
 
@@ -109,7 +122,8 @@ func signin(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var hashedPassword string
-	err = db.QueryRow("SELECT password_hash FROM users WHERE username = ?", creds.Username).Scan(&hashedPassword)
+	var verified bool
+	err = db.QueryRow("SELECT password_hash, verified FROM users WHERE username = ?", creds.Username).Scan(&hashedPassword, &verified)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			w.WriteHeader(http.StatusUnauthorized)
@@ -124,21 +138,61 @@ func signin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sessionToken := base64.StdEncoding.EncodeToString(make([]byte, 32))
-	_, err = rand.Read([]byte(sessionToken))
+	if !verified {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	if err := establishSession(w, creds.Username); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Also issue a JWT access/refresh pair alongside the session cookie, so
+	// non-browser clients can authenticate with Authorization: Bearer
+	// instead of a cookie jar.
+	accessToken, refreshID, refreshToken, err := issueTokenPair(creds.Username)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	expiresAt := time.Now().Add(sessionDuration)
-	sessions[sessionToken] = session{username: creds.Username, expiry: expiresAt}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"accessToken":  accessToken,
+		"refreshId":    refreshID,
+		"refreshToken": refreshToken,
+	})
+}
+
+// establishSession mints a new session for username, stores it, and sets the
+// session_token cookie on w. It is shared by the password and OAuth sign-in
+// paths.
+func establishSession(w http.ResponseWriter, username string) error {
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return err
+	}
+	sessionToken := base64.StdEncoding.EncodeToString(tokenBytes)
+
+	now := time.Now()
+	expiresAt := now.Add(sessionDuration)
+	if err := sessionStore.Put(auth.Session{
+		Token:    sessionToken,
+		Username: username,
+		Expiry:   expiresAt,
+		Created:  now,
+		LastSeen: now,
+	}); err != nil {
+		return err
+	}
 
 	http.SetCookie(w, &http.Cookie{
 		Name:    "session_token",
 		Value:   sessionToken,
 		Expires: expiresAt,
 	})
+	return nil
 }
 
 func logout(w http.ResponseWriter, r *http.Request) {
@@ -154,7 +208,10 @@ func logout(w http.ResponseWriter, r *http.Request) {
 
 	sessionToken := c.Value
 
-delete(sessions, sessionToken)
+	if err := sessionStore.Delete(sessionToken); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
 
 	http.SetCookie(w, &http.Cookie{
 		Name:    "session_token",
@@ -175,18 +232,35 @@ func checkAuth(w http.ResponseWriter, r *http.Request) {
 	}
 
 	sessionToken := c.Value
-	userSession, exists := sessions[sessionToken]
+	userSession, exists, err := sessionStore.Get(sessionToken)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
 	if !exists {
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
 
-	if userSession.isExpired() {
-		delete(sessions, sessionToken)
+	if userSession.IsExpired() {
+		sessionStore.Delete(sessionToken)
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
 
+	var verified bool
+	if err := db.QueryRow("SELECT verified FROM users WHERE username = ?", userSession.Username).Scan(&verified); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if !verified {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	userSession.LastSeen = time.Now()
+	sessionStore.Put(userSession)
+
 	w.WriteHeader(http.StatusOK)
 }
 
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type OllamaRequest struct {
+	Model  string `json:"model"`
+	Prompt string `json:"prompt"`
+	Stream bool   `json:"stream"`
+}
+
+type OllamaResponse struct {
+	Response string `json:"response"`
+	Done     bool   `json:"done"`
+}
+
+var (
+	// chatResponseDeadline bounds how long a single /api/chat request may
+	// run in total, set from -chat-response-deadline in main().
+	chatResponseDeadline = 2 * time.Minute
+	// chatIdleDeadline bounds how long /api/chat may go without a chunk
+	// from the upstream model before the stream is aborted, set from
+	// -chat-idle-deadline in main().
+	chatIdleDeadline = 30 * time.Second
+	// chatHeartbeatInterval is how often an SSE comment line is sent to
+	// keep intermediaries from closing an otherwise-quiet stream, set from
+	// -chat-heartbeat-interval in main().
+	chatHeartbeatInterval = 15 * time.Second
+)
+
+// idleTimer fires C when it has gone unreset for its duration, so a select
+// loop can react to a stalled read the same way it reacts to context
+// cancellation. Modeled on the deadlineTimer used by gVisor's netstack to
+// cancel blocked reads.
+//
+// Once C has fired, reset becomes a no-op: a select can race a fired timer
+// against a line that arrived in the same instant and pick either one, so
+// reset must tolerate being called after fire without rearming the timer
+// and closing C a second time.
+type idleTimer struct {
+	timer *time.Timer
+
+	mu    sync.Mutex
+	fired bool
+	C     chan struct{}
+}
+
+func newIdleTimer(d time.Duration) *idleTimer {
+	it := &idleTimer{C: make(chan struct{})}
+	it.timer = time.AfterFunc(d, it.fire)
+	return it
+}
+
+func (it *idleTimer) fire() {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+	if it.fired {
+		return
+	}
+	it.fired = true
+	close(it.C)
+}
+
+func (it *idleTimer) reset(d time.Duration) {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+	if it.fired {
+		return
+	}
+	it.timer.Reset(d)
+}
+
+func (it *idleTimer) stop() {
+	it.timer.Stop()
+}
+
+// writeSSEEvent writes a single named SSE frame and flushes it immediately.
+func writeSSEEvent(w http.ResponseWriter, flusher http.Flusher, event, data string) {
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+	flusher.Flush()
+}
+
+// chat proxies a prompt to the local Ollama server and streams its response
+// back as SSE. It bounds the whole exchange with chatResponseDeadline, and
+// cancels the upstream request if either the client disconnects or the
+// upstream goes quiet for longer than chatIdleDeadline.
+func chat(w http.ResponseWriter, r *http.Request) {
+	prompt := r.URL.Query().Get("prompt")
+	if prompt == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	body, err := json.Marshal(OllamaRequest{Model: "chat", Prompt: prompt, Stream: true})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), chatResponseDeadline)
+	defer cancel()
+
+	llmReq, err := http.NewRequestWithContext(ctx, "POST", "http://localhost:11434/api/generate", bytes.NewReader(body))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	llmReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(llmReq)
+	if err != nil {
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	idle := newIdleTimer(chatIdleDeadline)
+	defer idle.stop()
+
+	heartbeat := time.NewTicker(chatHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	// bufio.Scanner blocks on Read, so it runs on its own goroutine; the
+	// select loop below cancels the outbound request (which unblocks the
+	// Read) instead of waiting on the scanner directly. A single Read can
+	// hand the scanner several lines before it blocks again, so a plain
+	// `lines <- scanner.Text()` can still be stranded after chat returns
+	// (cancel() only unblocks the *next* Read, not a pending buffered
+	// line). done is closed when chat returns so the goroutine's send has
+	// somewhere to bail out to instead of blocking forever.
+	lines := make(chan string)
+	scanErr := make(chan error, 1)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			select {
+			case lines <- scanner.Text():
+			case <-done:
+				return
+			}
+		}
+		select {
+		case scanErr <- scanner.Err():
+		case <-done:
+		}
+	}()
+
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				if err := <-scanErr; err != nil && ctx.Err() == nil {
+					log.Printf("chat: reading upstream response: %v", err)
+					writeSSEEvent(w, flusher, "error", "upstream connection lost")
+				} else {
+					writeSSEEvent(w, flusher, "done", "")
+				}
+				return
+			}
+			idle.reset(chatIdleDeadline)
+
+			var ollamaResp OllamaResponse
+			if err := json.Unmarshal([]byte(line), &ollamaResp); err != nil {
+				continue
+			}
+			writeSSEEvent(w, flusher, "message", ollamaResp.Response)
+			if ollamaResp.Done {
+				writeSSEEvent(w, flusher, "done", "")
+				return
+			}
+
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			flusher.Flush()
+
+		case <-idle.C:
+			cancel()
+			writeSSEEvent(w, flusher, "error", "upstream idle timeout")
+			return
+
+		case <-ctx.Done():
+			cancel()
+			writeSSEEvent(w, flusher, "error", "request timed out or client disconnected")
+			return
+		}
+	}
+}
@@ -3,13 +3,42 @@ package main
 import (
 	"bufio"
 	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/base64"
+	"encoding/pem"
 	"fmt"
 	"log"
 	"os"
 	"strings"
+	"time"
 )
 
+// adminCertValidity is how long a certificate minted by enroll-cert remains
+// valid before the operator has to re-enroll.
+const adminCertValidity = 365 * 24 * time.Hour
+
+// listActiveSessionsCLI prints every session recorded in the sessions table.
+// It only has data to show when the server was started with
+// -sqlite-sessions; the in-memory store keeps sessions out of the database.
+func listActiveSessionsCLI() {
+	rows, err := db.Query("SELECT username, expiry, last_seen FROM sessions")
+	if err != nil {
+		fmt.Printf("no session data available (is the server running with -sqlite-sessions?): %v\n", err)
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var username, expiry, lastSeen string
+		if err := rows.Scan(&username, &expiry, &lastSeen); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("\nUser: %s\nExpires: %s\nLast seen: %s\n", username, expiry, lastSeen)
+	}
+}
+
 func runCLI() {
 	reader := bufio.NewReader(os.Stdin)
 	for {
@@ -22,6 +51,10 @@ func runCLI() {
 			generateInviteCodeCLI()
 		case "read-inquiries":
 			readInquiriesCLI()
+		case "list-sessions":
+			listActiveSessionsCLI()
+		case "enroll-cert":
+			enrollCertCLI(reader)
 		case "exit":
 			return
 		default:
@@ -47,6 +80,54 @@ func readInquiriesCLI() {
 	}
 }
 
+// enrollCertCLI mints an admin client certificate the same way crowdsec
+// enrolls a bouncer: it generates a key and CSR locally, signs the CSR
+// against the server's internal CA, and writes the key/cert/CA bundle to
+// disk for the operator to hand to nrvctl.
+func enrollCertCLI(reader *bufio.Reader) {
+	fmt.Print("Common name for this operator: ")
+	cn, _ := reader.ReadString('\n')
+	cn = strings.TrimSpace(cn)
+	if cn == "" {
+		fmt.Println("enroll-cert: a common name is required")
+		return
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	csrDER, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: cn, OrganizationalUnit: []string{adminCertOU}},
+	}, key)
+	if err != nil {
+		log.Fatal(err)
+	}
+	csrPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrDER})
+
+	certPEM, err := internalCA.SignCSR(csrPEM, adminCertValidity)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	if err := os.WriteFile(cn+".key.pem", keyPEM, 0600); err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(cn+".cert.pem", certPEM, 0644); err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile("ca.cert.pem", internalCA.CertPEM(), 0644); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("Wrote %s.key.pem, %s.cert.pem and ca.cert.pem.\n", cn, cn)
+	fmt.Printf("Add %q to ADMIN_CERT_CNS and start the server with -client-ca ca.cert.pem, then:\n", cn)
+	fmt.Printf("  nrvctl -cert %s.cert.pem -key %s.key.pem generate-invite\n", cn, cn)
+}
+
 func generateInviteCodeCLI() {
 	b := make([]byte, 16)
 	_, err := rand.Read(b)
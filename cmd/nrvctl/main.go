@@ -0,0 +1,92 @@
+// Command nrvctl is the secure remote equivalent of the server's
+// generate-invite and read-inquiries CLI commands: it authenticates with a
+// client certificate minted by the server's enroll-cert command instead of
+// putting a secret in a query string.
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+)
+
+func main() {
+	server := flag.String("server", "https://localhost:443", "base URL of the nrv.dev-webapp server")
+	certFile := flag.String("cert", "", "client certificate, from enroll-cert (PEM)")
+	keyFile := flag.String("key", "", "client private key, from enroll-cert (PEM)")
+	caFile := flag.String("ca", "", "server CA certificate (PEM), if it isn't signed by a public CA")
+	flag.Parse()
+
+	if flag.NArg() < 1 || *certFile == "" || *keyFile == "" {
+		fmt.Fprintln(os.Stderr, "usage: nrvctl -cert cert.pem -key key.pem [-server URL] [-ca ca.pem] <generate-invite|inquiries>")
+		os.Exit(2)
+	}
+
+	client, err := newClient(*certFile, *keyFile, *caFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	switch flag.Arg(0) {
+	case "generate-invite":
+		generateInvite(client, *server)
+	case "inquiries":
+		listInquiries(client, *server)
+	default:
+		log.Fatalf("unknown command: %s", flag.Arg(0))
+	}
+}
+
+func newClient(certFile, keyFile, caFile string) (*http.Client, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{Certificates: []tls.Certificate{cert}}
+
+	if caFile != "" {
+		caPEM, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("nrvctl: failed to parse %s", caFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}, nil
+}
+
+func generateInvite(client *http.Client, server string) {
+	resp, err := client.Post(server+"/api/generate-invite-code", "application/json", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer resp.Body.Close()
+	io.Copy(os.Stdout, resp.Body)
+}
+
+func listInquiries(client *http.Client, server string) {
+	resp, err := client.Get(server + "/api/inquiries")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var inquiries []json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&inquiries); err != nil {
+		log.Fatal(err)
+	}
+	for _, inquiry := range inquiries {
+		fmt.Println(string(inquiry))
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"nrv.dev-webapp/pkg/auth/jwt"
+)
+
+const (
+	accessTokenDuration  = 15 * time.Minute
+	refreshTokenDuration = 30 * 24 * time.Hour
+)
+
+// jwtSigner issues and verifies access tokens. It is nil until
+// loadJWTSigner runs in main(), which happens before any handler is
+// registered; handlers use it without a nil check.
+var jwtSigner *jwt.Signer
+
+// refreshStore persists refresh tokens for /api/token/refresh and
+// /api/token/revoke.
+var refreshStore *jwt.RefreshStore
+
+// loadJWTSigner builds jwtSigner from JWT_RSA_PRIVATE_KEY_FILE if set,
+// falling back to an HS256 signer keyed by JWT_SIGNING_KEY, or a
+// process-local random secret if neither is configured.
+func loadJWTSigner() {
+	if keyFile := os.Getenv("JWT_RSA_PRIVATE_KEY_FILE"); keyFile != "" {
+		key, err := loadRSAPrivateKey(keyFile)
+		if err != nil {
+			log.Fatalf("jwt: failed to load %s: %v", keyFile, err)
+		}
+		jwtSigner = jwt.NewRS256Signer(key)
+		return
+	}
+
+	secret := []byte(os.Getenv("JWT_SIGNING_KEY"))
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			log.Fatal(err)
+		}
+	}
+	jwtSigner = jwt.NewHS256Signer(secret)
+}
+
+func loadRSAPrivateKey(path string) (*rsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, os.ErrInvalid
+	}
+	return x509.ParsePKCS1PrivateKey(block.Bytes)
+}
+
+// adminUsernames lists the usernames granted the "admin" role, read from
+// ADMIN_USERNAMES (comma-separated) so it can be changed without a
+// redeploy.
+func adminUsernames() map[string]bool {
+	admins := make(map[string]bool)
+	for _, u := range strings.Split(os.Getenv("ADMIN_USERNAMES"), ",") {
+		u = strings.TrimSpace(u)
+		if u != "" {
+			admins[u] = true
+		}
+	}
+	return admins
+}
+
+func rolesForUser(username string) []string {
+	if adminUsernames()[username] {
+		return []string{"user", "admin"}
+	}
+	return []string{"user"}
+}
+
+// issueTokenPair mints an access token plus a refresh token for username.
+func issueTokenPair(username string) (accessToken, refreshID, refreshToken string, err error) {
+	now := time.Now()
+	jti := make([]byte, 16)
+	if _, err = rand.Read(jti); err != nil {
+		return "", "", "", err
+	}
+
+	accessToken, err = jwtSigner.Issue(jwt.Claims{
+		Subject:   username,
+		Username:  username,
+		Roles:     rolesForUser(username),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(accessTokenDuration).Unix(),
+		ID:        hex.EncodeToString(jti),
+	})
+	if err != nil {
+		return "", "", "", err
+	}
+
+	refreshID, refreshToken, err = refreshStore.Issue(username, refreshTokenDuration)
+	return accessToken, refreshID, refreshToken, err
+}
+
+type tokenRefreshRequest struct {
+	RefreshID    string `json:"refreshId"`
+	RefreshToken string `json:"refreshToken"`
+}
+
+type tokenRevokeRequest struct {
+	RefreshID    string `json:"refreshId"`
+	RefreshToken string `json:"refreshToken"`
+}
+
+// tokenRefresh exchanges a still-valid refresh token for a new access token.
+func tokenRefresh(w http.ResponseWriter, r *http.Request) {
+	var req tokenRefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	username, err := refreshStore.Verify(req.RefreshID, req.RefreshToken)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	now := time.Now()
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	accessToken, err := jwtSigner.Issue(jwt.Claims{
+		Subject:   username,
+		Username:  username,
+		Roles:     rolesForUser(username),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(accessTokenDuration).Unix(),
+		ID:        hex.EncodeToString(jti),
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"accessToken": accessToken})
+}
+
+// tokenRevoke invalidates a refresh token so it can no longer mint access
+// tokens. Like tokenRefresh, it requires the raw refresh token as proof of
+// possession, not just the (loggable, leakable) refresh id.
+func tokenRevoke(w http.ResponseWriter, r *http.Request) {
+	var req tokenRevokeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if _, err := refreshStore.Verify(req.RefreshID, req.RefreshToken); err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if err := refreshStore.Revoke(req.RefreshID, req.RefreshToken); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
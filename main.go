@@ -1,7 +1,7 @@
 package main
 
 import (
-	"bufio"
+	"context"
 	"database/sql"
 	"encoding/json"
 	"flag"
@@ -9,14 +9,24 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"runtime"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
+
+	"nrv.dev-webapp/pkg/auth"
+	"nrv.dev-webapp/pkg/auth/jwt"
+	"nrv.dev-webapp/pkg/auth/mtls"
 )
 
+// sessionSnapshotPath is where the in-memory session store is persisted on
+// shutdown and reloaded from on startup.
+const sessionSnapshotPath = "./sessions.snapshot"
+
 var db *sql.DB
 
 type Post struct {
@@ -50,17 +60,6 @@ type SysInfo struct {
 	LoadAvg       string `json:"loadAvg"`
 }
 
-type OllamaRequest struct {
-	Model  string `json:"model"`
-	Prompt string `json:"prompt"`
-	Stream bool   `json:"stream"`
-}
-
-type OllamaResponse struct {
-	Response string `json:"response"`
-	Done     bool   `json:"done"`
-}
-
 func getKernelVersion() string {
 	data, err := os.ReadFile("/proc/version")
 	if err != nil {
@@ -145,50 +144,29 @@ func inquire(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusCreated)
 }
 
-func chat(w http.ResponseWriter, r *http.Request) {
-	prompt := r.URL.Query().Get("prompt")
-	if prompt == "" {
-		w.WriteHeader(http.StatusBadRequest)
-		return
-	}
-
-	// Create a new request to the Ollama API
-	client := &http.Client{}
-	llmReq, err := http.NewRequest("POST", "http://localhost:11434/api/generate", strings.NewReader(fmt.Sprintf(`{"model": "chat", "prompt": "%s", "stream": true}`, prompt)))
+// listInquiries is the HTTP equivalent of the read-inquiries CLI command,
+// gated by requireAdmin in main() so it doesn't sit open to the internet.
+func listInquiries(w http.ResponseWriter, r *http.Request) {
+	rows, err := db.Query("SELECT name, email, message, ip_address, timestamp FROM inquiries")
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-
-	// Set the headers
-	llmReq.Header.Set("Content-Type", "application/json")
-
-	// Send the request
-	resp, err := client.Do(llmReq)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	defer resp.Body.Close()
-
-	// Stream the response back to the client
-	w.Header().Set("Content-Type", "text/event-stream")
-	w.Header().Set("Cache-Control", "no-cache")
-	w.Header().Set("Connection", "keep-alive")
+	defer rows.Close()
 
-	scanner := bufio.NewScanner(resp.Body)
-	for scanner.Scan() {
-		var ollamaResp OllamaResponse
-		err := json.Unmarshal(scanner.Bytes(), &ollamaResp)
-		if err != nil {
-			continue
-		}
-		fmt.Fprintf(w, "data: %s\n\n", ollamaResp.Response)
-		flusher, ok := w.(http.Flusher)
-		if ok {
-			flusher.Flush()
+	var inquiries []Inquiry
+	for rows.Next() {
+		var i Inquiry
+		var ipAddress, timestamp string
+		if err := rows.Scan(&i.Name, &i.Email, &i.Message, &ipAddress, &timestamp); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
 		}
+		inquiries = append(inquiries, i)
 	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(inquiries)
 }
 
 func initDB() {
@@ -211,10 +189,43 @@ func initDB() {
 		log.Fatal(err)
 	}
 
+	addColumnIfNotExists("users", "oauth_provider", "TEXT")
+	addColumnIfNotExists("users", "oauth_subject", "TEXT")
+	addColumnIfNotExists("users", "email", "TEXT")
+	addColumnIfNotExists("users", "verified", "BOOLEAN NOT NULL DEFAULT 0")
+
 	// Migrate initial content
 	migrateContent()
 }
 
+// addColumnIfNotExists adds column to table if it isn't already there.
+// SQLite has no "ADD COLUMN IF NOT EXISTS", so we check pragma table_info
+// first.
+func addColumnIfNotExists(table, column, sqlType string) {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid int
+		var name, colType string
+		var notNull, pk int
+		var dfltValue sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			log.Fatal(err)
+		}
+		if name == column {
+			return
+		}
+	}
+
+	if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, sqlType)); err != nil {
+		log.Fatal(err)
+	}
+}
+
 func migrateContent() {
 	// Check if posts exist
 	var count int
@@ -246,13 +257,75 @@ func visitorMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// newSessionStore builds the session backend selected by -sqlite-sessions.
+// When using the in-memory store, any snapshot left by a previous run is
+// reloaded so a restart doesn't log everyone out.
+func newSessionStore(useSQLite bool) (auth.SessionStore, error) {
+	if useSQLite {
+		return auth.NewSQLiteStore(db)
+	}
+
+	store := auth.NewMemoryStore()
+	if f, err := os.Open(sessionSnapshotPath); err == nil {
+		loadErr := store.LoadFrom(f)
+		f.Close()
+		if loadErr != nil {
+			return nil, loadErr
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	return store, nil
+}
+
 func main() {
 	port := flag.String("port", "443", "port to listen on")
+	sqliteSessions := flag.Bool("sqlite-sessions", false, "persist sessions in SQLite instead of the in-memory snapshot store")
+	sessionSweepInterval := flag.Duration("session-sweep-interval", 10*time.Minute, "how often to sweep expired sessions")
+	clientCA := flag.String("client-ca", "", "PEM bundle of CAs trusted to sign admin client certificates; enables optional mTLS when set")
+	flag.DurationVar(&chatResponseDeadline, "chat-response-deadline", chatResponseDeadline, "total deadline for a single /api/chat response")
+	flag.DurationVar(&chatIdleDeadline, "chat-idle-deadline", chatIdleDeadline, "deadline after which an idle /api/chat stream is canceled")
+	flag.DurationVar(&chatHeartbeatInterval, "chat-heartbeat-interval", chatHeartbeatInterval, "how often to send an SSE heartbeat during /api/chat")
 	flag.Parse()
 
 	initDB()
 	defer db.Close()
 
+	loadOAuthProviders()
+
+	loadJWTSigner()
+	var err error
+	refreshStore, err = jwt.NewRefreshStore(db)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	tokenStore, err = auth.NewTokenStore(db)
+	if err != nil {
+		log.Fatal(err)
+	}
+	loadMailer()
+
+	internalCA, err = mtls.LoadOrCreateCA(db)
+	if err != nil {
+		log.Fatal(err)
+	}
+	tlsConfig, err := buildTLSConfig(*clientCA)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	store, err := newSessionStore(*sqliteSessions)
+	if err != nil {
+		log.Fatal(err)
+	}
+	sessionStore = store
+
+	sweepStop := make(chan struct{})
+	go auth.RunSweeper(sessionStore, *sessionSweepInterval, sweepStop)
+	go runRateLimiterSweeper(resetRequestsByEmail, passwordResetRateWindow, sweepStop)
+	go runRateLimiterSweeper(resetRequestsByIP, passwordResetRateWindow, sweepStop)
+
 	go runCLI()
 
 	fs := http.FileServer(http.Dir("."))
@@ -285,10 +358,28 @@ func main() {
 	http.HandleFunc("/api/signin", signin)
 	http.HandleFunc("/api/logout", logout)
 	http.HandleFunc("/api/check-auth", checkAuth)
-	http.HandleFunc("/api/generate-invite-code", generateInviteCode)
+	http.HandleFunc("/api/verify", verifyEmail)
+	http.HandleFunc("/api/password/reset-request", passwordResetRequest)
+	http.HandleFunc("/api/password/reset", passwordReset)
+	http.Handle("/api/generate-invite-code", requireAdmin(http.HandlerFunc(generateInviteCode)))
+	http.Handle("/api/inquiries", requireAdmin(http.HandlerFunc(listInquiries)))
 	http.HandleFunc("/api/inquire", inquire)
 	http.HandleFunc("/api/chat", chat)
 
+	http.HandleFunc("/api/token/refresh", tokenRefresh)
+	http.HandleFunc("/api/token/revoke", tokenRevoke)
+
+	http.HandleFunc("/api/oauth/", func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/start"):
+			oauthStart(w, r)
+		case strings.HasSuffix(r.URL.Path, "/callback"):
+			oauthCallback(w, r)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
 	http.HandleFunc("/api/posts", func(w http.ResponseWriter, r *http.Request) {
 		rows, err := db.Query("SELECT id, slug, title, content, date FROM posts")
 		if err != nil {
@@ -333,7 +424,45 @@ func main() {
 	})
 
 	log.Printf("Starting server on https://localhost:%s", *port)
-	if err := http.ListenAndServeTLS(":"+*port, "cert.pem", "key.pem", nil); err != nil {
+	server := &http.Server{
+		Addr:      ":" + *port,
+		TLSConfig: tlsConfig,
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		log.Print("main: shutting down")
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			log.Printf("main: graceful shutdown failed: %v", err)
+		}
+	}()
+
+	if err := server.ListenAndServeTLS("cert.pem", "key.pem"); err != nil && err != http.ErrServerClosed {
 		log.Fatal(err)
 	}
+
+	close(sweepStop)
+	if memStore, ok := sessionStore.(*auth.MemoryStore); ok {
+		snapshotSessions(memStore)
+	}
+}
+
+// snapshotSessions persists the in-memory session store to
+// sessionSnapshotPath so a restart doesn't log everyone out. It's called on
+// a clean shutdown (see the signal handling in main), not via defer, since
+// os.Exit from log.Fatal would skip a deferred call entirely.
+func snapshotSessions(memStore *auth.MemoryStore) {
+	f, err := os.Create(sessionSnapshotPath)
+	if err != nil {
+		log.Printf("auth: failed to snapshot sessions: %v", err)
+		return
+	}
+	defer f.Close()
+	if err := memStore.SnapshotTo(f); err != nil {
+		log.Printf("auth: failed to snapshot sessions: %v", err)
+	}
 }
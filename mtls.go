@@ -0,0 +1,80 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"nrv.dev-webapp/pkg/auth/jwt"
+	"nrv.dev-webapp/pkg/auth/mtls"
+)
+
+// adminCertOU is the Subject OU an enrolled admin client certificate must
+// carry. Every cert ca issues via enroll-cert is stamped with it.
+const adminCertOU = "admins"
+
+// internalCA issues and signs admin client certificates. It is initialized
+// in main() before the HTTP server starts listening.
+var internalCA *mtls.CA
+
+// mtlsEnabled is true once the server has been started with -client-ca, so
+// a missing flag leaves admin endpoints guarded by JWT alone instead of
+// silently open to anyone who can connect.
+var mtlsEnabled bool
+
+// adminCertCommonNames lists the CNs allowed to present an admin client
+// certificate, from ADMIN_CERT_CNS (comma-separated).
+func adminCertCommonNames() []string {
+	var cns []string
+	for _, cn := range strings.Split(os.Getenv("ADMIN_CERT_CNS"), ",") {
+		cn = strings.TrimSpace(cn)
+		if cn != "" {
+			cns = append(cns, cn)
+		}
+	}
+	return cns
+}
+
+// buildTLSConfig loads clientCAFile, if given, into a *tls.Config that
+// accepts but does not require a client certificate; RequireClientCert (or
+// requireAdmin) is what rejects requests that didn't present one where it
+// matters. It returns a nil config when clientCAFile is empty, so main can
+// fall back to http.ListenAndServeTLS's defaults.
+func buildTLSConfig(clientCAFile string) (*tls.Config, error) {
+	if clientCAFile == "" {
+		return nil, nil
+	}
+
+	caPEM, err := os.ReadFile(clientCAFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("mtls: failed to parse %s", clientCAFile)
+	}
+
+	mtlsEnabled = true
+	return &tls.Config{
+		ClientCAs:  pool,
+		ClientAuth: tls.VerifyClientCertIfGiven,
+	}, nil
+}
+
+// requireAdmin accepts either an admin client certificate, when mTLS is
+// enabled, or an admin JWT, so the same CLI-equivalent endpoints work for
+// an operator enrolled via nrvctl and for a plain Authorization: Bearer
+// client.
+func requireAdmin(next http.Handler) http.Handler {
+	withJWT := jwt.RequireJWT(jwtSigner, "admin")(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if mtlsEnabled && mtls.Verify(r, adminCertOU, adminCertCommonNames()) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		withJWT.ServeHTTP(w, r)
+	})
+}
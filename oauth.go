@@ -0,0 +1,187 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"nrv.dev-webapp/pkg/auth/oauth"
+)
+
+// oauthProviders holds the providers enabled for this process, keyed by the
+// name used in /api/oauth/{provider}/... URLs. It is populated in main() from
+// whichever provider credentials are present in the environment.
+var oauthProviders = map[string]oauth.Provider{}
+
+// oauthStateSecret signs the state cookie so a callback can't be replayed
+// with a state value an attacker chose. It's generated at startup unless
+// OAUTH_STATE_SECRET is set, which is required for it to survive a restart
+// in a multi-instance deployment.
+var oauthStateSecret []byte
+
+func loadOAuthProviders() {
+	if id, secret := os.Getenv("GITHUB_CLIENT_ID"), os.Getenv("GITHUB_CLIENT_SECRET"); id != "" && secret != "" {
+		redirectURL := os.Getenv("GITHUB_REDIRECT_URL")
+		oauthProviders["github"] = oauth.NewGitHubProvider(id, secret, redirectURL)
+	}
+	if id, secret := os.Getenv("MICROSOFT_CLIENT_ID"), os.Getenv("MICROSOFT_CLIENT_SECRET"); id != "" && secret != "" {
+		redirectURL := os.Getenv("MICROSOFT_REDIRECT_URL")
+		oauthProviders["microsoft"] = oauth.NewMicrosoftProvider(id, secret, redirectURL)
+	}
+
+	if secret := os.Getenv("OAUTH_STATE_SECRET"); secret != "" {
+		oauthStateSecret = []byte(secret)
+		return
+	}
+	oauthStateSecret = make([]byte, 32)
+	if _, err := rand.Read(oauthStateSecret); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// newSignedOAuthState returns a random state token with an HMAC signature
+// appended, so oauthCallback can tell the value came from us.
+func newSignedOAuthState() (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	rawHex := hex.EncodeToString(raw)
+	return rawHex + "." + signOAuthState(rawHex), nil
+}
+
+func signOAuthState(raw string) string {
+	mac := hmac.New(sha256.New, oauthStateSecret)
+	mac.Write([]byte(raw))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func validOAuthState(state string) bool {
+	parts := strings.SplitN(state, ".", 2)
+	if len(parts) != 2 {
+		return false
+	}
+	return hmac.Equal([]byte(parts[1]), []byte(signOAuthState(parts[0])))
+}
+
+// oauthProviderFromPath extracts the provider name from a
+// /api/oauth/{provider}/{start,callback} path.
+func oauthProviderFromPath(path, suffix string) (oauth.Provider, bool) {
+	name := strings.TrimSuffix(strings.TrimPrefix(path, "/api/oauth/"), suffix)
+	provider, ok := oauthProviders[name]
+	return provider, ok
+}
+
+func oauthStart(w http.ResponseWriter, r *http.Request) {
+	provider, ok := oauthProviderFromPath(r.URL.Path, "/start")
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	state, err := newSignedOAuthState()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     "oauth_state",
+		Value:    state,
+		Expires:  time.Now().Add(10 * time.Minute),
+		HttpOnly: true,
+	})
+
+	http.Redirect(w, r, provider.AuthURL(state), http.StatusFound)
+}
+
+func oauthCallback(w http.ResponseWriter, r *http.Request) {
+	provider, ok := oauthProviderFromPath(r.URL.Path, "/callback")
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	stateCookie, err := r.Cookie("oauth_state")
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	state := r.URL.Query().Get("state")
+	if state == "" || state != stateCookie.Value || !validOAuthState(state) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	code := r.URL.Query().Get("code")
+	if code == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	token, err := provider.Exchange(ctx, code)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	profile, err := provider.FetchProfile(ctx, token)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	username, err := upsertOAuthUser(provider.Name(), profile)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := establishSession(w, username); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/", http.StatusFound)
+}
+
+// upsertOAuthUser finds the user previously linked to (provider, subject),
+// or creates one. It returns the account's username.
+func upsertOAuthUser(provider string, profile oauth.Profile) (string, error) {
+	var username string
+	err := db.QueryRow("SELECT username FROM users WHERE oauth_provider = ? AND oauth_subject = ?", provider, profile.Subject).Scan(&username)
+	if err == nil {
+		return username, nil
+	}
+	if err != sql.ErrNoRows {
+		return "", err
+	}
+
+	username = profile.Username
+	if username == "" {
+		username = fmt.Sprintf("%s-%s", provider, profile.Subject)
+	}
+
+	// The provider has already confirmed the account's identity, so there's
+	// no separate email-verification step for OAuth sign-ins.
+	_, err = db.Exec("INSERT INTO users (username, email, verified, oauth_provider, oauth_subject) VALUES (?, ?, 1, ?, ?)", username, profile.Email, provider, profile.Subject)
+	if err != nil {
+		// The username is already taken by a different account; disambiguate
+		// with the provider subject so sign-in still succeeds.
+		username = fmt.Sprintf("%s-%s", username, profile.Subject)
+		if _, err = db.Exec("INSERT INTO users (username, email, verified, oauth_provider, oauth_subject) VALUES (?, ?, 1, ?, ?)", username, profile.Email, provider, profile.Subject); err != nil {
+			return "", err
+		}
+	}
+
+	return username, nil
+}
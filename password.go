@@ -0,0 +1,201 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"nrv.dev-webapp/pkg/auth"
+	"nrv.dev-webapp/pkg/auth/mail"
+)
+
+const (
+	verificationTokenDuration  = 24 * time.Hour
+	passwordResetTokenDuration = time.Hour
+	passwordResetRateWindow    = 5 * time.Minute
+)
+
+// tokenStore backs email verification and password reset. It is
+// initialized in main() before the HTTP server starts listening.
+var tokenStore *auth.TokenStore
+
+// mailer sends the verification and password-reset emails. It is
+// initialized in main() before the HTTP server starts listening.
+var mailer *mail.Mailer
+
+func loadMailer() {
+	mailer = mail.New(mail.Config{
+		Host:     os.Getenv("SMTP_HOST"),
+		Port:     os.Getenv("SMTP_PORT"),
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     os.Getenv("SMTP_FROM"),
+	})
+}
+
+// publicBaseURL returns the origin used to build links mailed to users,
+// e.g. "https://nrv.dev".
+func publicBaseURL() string {
+	if u := os.Getenv("PUBLIC_BASE_URL"); u != "" {
+		return u
+	}
+	return "https://localhost"
+}
+
+// rateLimiter allows one action per key every window.
+type rateLimiter struct {
+	mu     sync.Mutex
+	last   map[string]time.Time
+	window time.Duration
+}
+
+func newRateLimiter(window time.Duration) *rateLimiter {
+	return &rateLimiter{last: make(map[string]time.Time), window: window}
+}
+
+func (r *rateLimiter) allow(key string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if last, ok := r.last[key]; ok && time.Since(last) < r.window {
+		return false
+	}
+	r.last[key] = time.Now()
+	return true
+}
+
+// sweep evicts entries whose window has already passed, so last doesn't
+// grow without bound as distinct emails/IPs hit the limiter over time.
+func (r *rateLimiter) sweep() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for key, last := range r.last {
+		if now.Sub(last) >= r.window {
+			delete(r.last, key)
+		}
+	}
+}
+
+var (
+	resetRequestsByEmail = newRateLimiter(passwordResetRateWindow)
+	resetRequestsByIP    = newRateLimiter(passwordResetRateWindow)
+)
+
+// runRateLimiterSweeper calls limiter.sweep() at the given interval until
+// stop is closed. Modeled on auth.RunSweeper; meant to be run in its own
+// goroutine.
+func runRateLimiterSweeper(limiter *rateLimiter, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			limiter.sweep()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// verifyEmail consumes a single-use token minted at signup and marks the
+// account it was issued to as verified.
+func verifyEmail(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	err := tokenStore.Consume("verify", token, func(tx *sql.Tx, username string) error {
+		_, err := tx.Exec("UPDATE users SET verified = 1 WHERE username = ?", username)
+		return err
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+type passwordResetRequestBody struct {
+	Email string `json:"email"`
+}
+
+// passwordResetRequest enqueues a password-reset email if the address
+// belongs to an account, rate-limited per email and per IP so it can't be
+// used to spam a mailbox. It answers 202 whether or not the address has an
+// account, though it replies sooner when it has none.
+func passwordResetRequest(w http.ResponseWriter, r *http.Request) {
+	var req passwordResetRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if !resetRequestsByEmail.allow(req.Email) || !resetRequestsByIP.allow(r.RemoteAddr) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		return
+	}
+
+	var username string
+	err := db.QueryRow("SELECT username FROM users WHERE email = ?", req.Email).Scan(&username)
+	if err != nil {
+		// Don't reveal whether the address has an account.
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	token, err := tokenStore.Issue("reset", username, passwordResetTokenDuration)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	link := fmt.Sprintf("%s/api/password/reset?token=%s", publicBaseURL(), token)
+	if err := mailer.SendPasswordReset(req.Email, link); err != nil {
+		log.Printf("mail: failed to send password reset email: %v", err)
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+type passwordResetBody struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"newPassword"`
+}
+
+// passwordReset consumes a reset token and updates the account's password
+// hash in the same transaction, so the token can't be replayed.
+func passwordReset(w http.ResponseWriter, r *http.Request) {
+	var req passwordResetBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	hashedPassword, err := hashPassword(req.NewPassword)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = tokenStore.Consume("reset", req.Token, func(tx *sql.Tx, username string) error {
+		_, err := tx.Exec("UPDATE users SET password_hash = ? WHERE username = ?", hashedPassword, username)
+		return err
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
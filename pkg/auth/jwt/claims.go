@@ -0,0 +1,31 @@
+// Package jwt issues and verifies stateless access tokens so the API can be
+// consumed from non-browser clients (mobile, the CLI, CI) without a
+// server-side session.
+package jwt
+
+import "time"
+
+// Claims are the access-token payload.
+type Claims struct {
+	Subject   string   `json:"sub"`
+	Username  string   `json:"username"`
+	Roles     []string `json:"roles"`
+	IssuedAt  int64    `json:"iat"`
+	ExpiresAt int64    `json:"exp"`
+	ID        string   `json:"jti"`
+}
+
+// Expired reports whether the claims' exp has passed.
+func (c Claims) Expired() bool {
+	return time.Unix(c.ExpiresAt, 0).Before(time.Now())
+}
+
+// HasRole reports whether role is among the claims' roles.
+func (c Claims) HasRole(role string) bool {
+	for _, r := range c.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
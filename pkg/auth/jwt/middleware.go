@@ -0,0 +1,50 @@
+package jwt
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+type contextKey int
+
+const claimsContextKey contextKey = iota
+
+// ClaimsFromContext returns the claims RequireJWT injected into the request
+// context, if any.
+func ClaimsFromContext(ctx context.Context) (Claims, bool) {
+	c, ok := ctx.Value(claimsContextKey).(Claims)
+	return c, ok
+}
+
+// RequireJWT parses the Authorization: Bearer header, verifies the token
+// against signer, and (if roles are given) requires the claims to carry
+// every one of them, before injecting the claims into the request context
+// and calling next.
+func RequireJWT(signer *Signer, roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			const prefix = "Bearer "
+			authz := r.Header.Get("Authorization")
+			if !strings.HasPrefix(authz, prefix) {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			claims, err := signer.Verify(strings.TrimPrefix(authz, prefix))
+			if err != nil {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			for _, role := range roles {
+				if !claims.HasRole(role) {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), claimsContextKey, claims)))
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package jwt
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// RefreshStore persists refresh tokens, hashed, in a refresh_tokens table.
+// Only the hash is ever stored, so a leaked database backup doesn't hand out
+// usable tokens.
+type RefreshStore struct {
+	db *sql.DB
+}
+
+// NewRefreshStore creates the refresh_tokens table if it doesn't already
+// exist and returns a store backed by it.
+func NewRefreshStore(db *sql.DB) (*RefreshStore, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS refresh_tokens (
+		id         TEXT PRIMARY KEY,
+		token_hash TEXT NOT NULL,
+		username   TEXT NOT NULL,
+		expiry     DATETIME NOT NULL,
+		revoked    BOOLEAN NOT NULL DEFAULT 0
+	)`)
+	if err != nil {
+		return nil, err
+	}
+	return &RefreshStore{db: db}, nil
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Issue stores a new refresh token for username and returns its id and raw
+// value. The raw value is only ever available here; the store keeps just
+// its hash.
+func (s *RefreshStore) Issue(username string, ttl time.Duration) (id, token string, err error) {
+	idBytes := make([]byte, 16)
+	if _, err = rand.Read(idBytes); err != nil {
+		return "", "", err
+	}
+	id = hex.EncodeToString(idBytes)
+
+	tokenBytes := make([]byte, 32)
+	if _, err = rand.Read(tokenBytes); err != nil {
+		return "", "", err
+	}
+	token = hex.EncodeToString(tokenBytes)
+
+	_, err = s.db.Exec("INSERT INTO refresh_tokens (id, token_hash, username, expiry, revoked) VALUES (?, ?, ?, ?, 0)",
+		id, hashRefreshToken(token), username, time.Now().Add(ttl))
+	if err != nil {
+		return "", "", err
+	}
+	return id, token, nil
+}
+
+// Verify looks up the refresh token by id and checks that it isn't revoked
+// or expired and that its hash matches token. It returns the username it
+// was issued to.
+func (s *RefreshStore) Verify(id, token string) (string, error) {
+	var tokenHash, username string
+	var expiry time.Time
+	var revoked bool
+
+	row := s.db.QueryRow("SELECT token_hash, username, expiry, revoked FROM refresh_tokens WHERE id = ?", id)
+	if err := row.Scan(&tokenHash, &username, &expiry, &revoked); err != nil {
+		return "", err
+	}
+	if revoked {
+		return "", errors.New("jwt: refresh token revoked")
+	}
+	if expiry.Before(time.Now()) {
+		return "", errors.New("jwt: refresh token expired")
+	}
+	if !hmac.Equal([]byte(tokenHash), []byte(hashRefreshToken(token))) {
+		return "", errors.New("jwt: refresh token does not match")
+	}
+
+	return username, nil
+}
+
+// Revoke marks a refresh token as unusable. Like Verify, it requires the raw
+// token value alongside the id, so knowing the id alone (e.g. from a log
+// line) isn't enough to kill someone else's refresh session.
+func (s *RefreshStore) Revoke(id, token string) error {
+	var tokenHash string
+	row := s.db.QueryRow("SELECT token_hash FROM refresh_tokens WHERE id = ?", id)
+	if err := row.Scan(&tokenHash); err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(tokenHash), []byte(hashRefreshToken(token))) {
+		return errors.New("jwt: refresh token does not match")
+	}
+
+	_, err := s.db.Exec("UPDATE refresh_tokens SET revoked = 1 WHERE id = ?", id)
+	return err
+}
@@ -0,0 +1,80 @@
+package jwt
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func newTestRefreshStore(t *testing.T) *RefreshStore {
+	t.Helper()
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	store, err := NewRefreshStore(db)
+	if err != nil {
+		t.Fatalf("NewRefreshStore: %v", err)
+	}
+	return store
+}
+
+func TestRefreshStoreVerifyRoundTrip(t *testing.T) {
+	store := newTestRefreshStore(t)
+
+	id, token, err := store.Issue("alice", time.Hour)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	username, err := store.Verify(id, token)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if username != "alice" {
+		t.Errorf("Verify username = %q, want alice", username)
+	}
+}
+
+func TestRefreshStoreVerifyRejectsWrongToken(t *testing.T) {
+	store := newTestRefreshStore(t)
+
+	id, _, err := store.Issue("alice", time.Hour)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	if _, err := store.Verify(id, "not-the-token"); err == nil {
+		t.Fatal("Verify accepted a mismatched token")
+	}
+}
+
+// TestRefreshStoreRevokeRequiresToken guards against revoking a refresh
+// session by id alone: knowing the id (e.g. from a log line) must not be
+// enough to kill someone else's session.
+func TestRefreshStoreRevokeRequiresToken(t *testing.T) {
+	store := newTestRefreshStore(t)
+
+	id, token, err := store.Issue("alice", time.Hour)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	if err := store.Revoke(id, "not-the-token"); err == nil {
+		t.Fatal("Revoke accepted a mismatched token")
+	}
+	if _, err := store.Verify(id, token); err != nil {
+		t.Fatalf("token was revoked despite a failed Revoke call: %v", err)
+	}
+
+	if err := store.Revoke(id, token); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	if _, err := store.Verify(id, token); err == nil {
+		t.Fatal("Verify accepted a token after a successful Revoke")
+	}
+}
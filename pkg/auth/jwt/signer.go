@@ -0,0 +1,130 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// Signer issues and verifies JWTs. It signs with HS256 when only a shared
+// secret is configured, or RS256 once an RSA key pair is loaded.
+type Signer struct {
+	hmacSecret []byte
+	rsaKey     *rsa.PrivateKey
+	rsaPub     *rsa.PublicKey
+}
+
+// NewHS256Signer returns a Signer that signs and verifies with the given
+// shared secret.
+func NewHS256Signer(secret []byte) *Signer {
+	return &Signer{hmacSecret: secret}
+}
+
+// NewRS256Signer returns a Signer that signs with key and verifies against
+// its public half.
+func NewRS256Signer(key *rsa.PrivateKey) *Signer {
+	return &Signer{rsaKey: key, rsaPub: &key.PublicKey}
+}
+
+func (s *Signer) alg() string {
+	if s.rsaKey != nil {
+		return "RS256"
+	}
+	return "HS256"
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+}
+
+// Issue encodes and signs claims, returning a compact "header.payload.signature" token.
+func (s *Signer) Issue(claims Claims) (string, error) {
+	headerJSON, err := json.Marshal(header{Alg: s.alg(), Typ: "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payloadJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(payloadJSON)
+
+	sig, err := s.sign(signingInput)
+	if err != nil {
+		return "", err
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+func (s *Signer) sign(signingInput string) ([]byte, error) {
+	if s.rsaKey != nil {
+		hashed := sha256.Sum256([]byte(signingInput))
+		return rsa.SignPKCS1v15(rand.Reader, s.rsaKey, crypto.SHA256, hashed[:])
+	}
+
+	mac := hmac.New(sha256.New, s.hmacSecret)
+	mac.Write([]byte(signingInput))
+	return mac.Sum(nil), nil
+}
+
+// Verify checks the token's signature and expiry and returns its claims.
+func (s *Signer) Verify(token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Claims{}, errors.New("jwt: malformed token")
+	}
+	signingInput := parts[0] + "." + parts[1]
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Claims{}, errors.New("jwt: malformed signature")
+	}
+
+	if err := s.verifySignature(signingInput, sig); err != nil {
+		return Claims{}, err
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return Claims{}, errors.New("jwt: malformed payload")
+	}
+
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return Claims{}, err
+	}
+
+	if claims.Expired() {
+		return Claims{}, errors.New("jwt: token expired")
+	}
+
+	return claims, nil
+}
+
+func (s *Signer) verifySignature(signingInput string, sig []byte) error {
+	if s.rsaPub != nil {
+		hashed := sha256.Sum256([]byte(signingInput))
+		if err := rsa.VerifyPKCS1v15(s.rsaPub, crypto.SHA256, hashed[:], sig); err != nil {
+			return errors.New("jwt: invalid signature")
+		}
+		return nil
+	}
+
+	expected, err := s.sign(signingInput)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal(expected, sig) {
+		return errors.New("jwt: invalid signature")
+	}
+	return nil
+}
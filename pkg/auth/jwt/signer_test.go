@@ -0,0 +1,93 @@
+package jwt
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func testClaims() Claims {
+	now := time.Now()
+	return Claims{
+		Subject:   "alice",
+		Username:  "alice",
+		Roles:     []string{"user"},
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(time.Hour).Unix(),
+		ID:        "test-jti",
+	}
+}
+
+func TestSignerVerifyRoundTrip(t *testing.T) {
+	s := NewHS256Signer([]byte("test-secret"))
+
+	token, err := s.Issue(testClaims())
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	claims, err := s.Verify(token)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if claims.Username != "alice" {
+		t.Errorf("Username = %q, want alice", claims.Username)
+	}
+}
+
+func TestSignerVerifyRejectsTamperedPayload(t *testing.T) {
+	s := NewHS256Signer([]byte("test-secret"))
+
+	token, err := s.Issue(testClaims())
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("token has %d parts, want 3", len(parts))
+	}
+	// Flip a byte in the payload so it no longer matches the signature.
+	tampered := parts[0] + "." + parts[1] + "x" + "." + parts[2]
+
+	if _, err := s.Verify(tampered); err == nil {
+		t.Fatal("Verify accepted a tampered token")
+	}
+}
+
+func TestSignerVerifyRejectsWrongSecret(t *testing.T) {
+	signed := NewHS256Signer([]byte("test-secret"))
+	token, err := signed.Issue(testClaims())
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	other := NewHS256Signer([]byte("a-different-secret"))
+	if _, err := other.Verify(token); err == nil {
+		t.Fatal("Verify accepted a token signed with a different secret")
+	}
+}
+
+func TestSignerVerifyRejectsExpiredToken(t *testing.T) {
+	s := NewHS256Signer([]byte("test-secret"))
+
+	claims := testClaims()
+	claims.ExpiresAt = time.Now().Add(-time.Minute).Unix()
+
+	token, err := s.Issue(claims)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	if _, err := s.Verify(token); err == nil {
+		t.Fatal("Verify accepted an expired token")
+	}
+}
+
+func TestSignerVerifyRejectsMalformedToken(t *testing.T) {
+	s := NewHS256Signer([]byte("test-secret"))
+
+	if _, err := s.Verify("not-a-jwt"); err == nil {
+		t.Fatal("Verify accepted a malformed token")
+	}
+}
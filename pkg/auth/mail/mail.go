@@ -0,0 +1,58 @@
+// Package mail sends the verification and password-reset emails the signup
+// flow needs, over plain SMTP.
+package mail
+
+import (
+	"fmt"
+	"log"
+	"net/smtp"
+)
+
+// Config holds the SMTP settings used to send mail. It should be populated
+// from env vars or a config file, not flags.
+type Config struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// Mailer sends transactional email over SMTP.
+type Mailer struct {
+	cfg Config
+}
+
+// New returns a Mailer for cfg.
+func New(cfg Config) *Mailer {
+	return &Mailer{cfg: cfg}
+}
+
+// SendVerification emails a link the recipient can visit to verify their
+// account.
+func (m *Mailer) SendVerification(to, link string) error {
+	return m.send(to, "Verify your email", fmt.Sprintf("Click the link below to verify your account:\n\n%s\n", link))
+}
+
+// SendPasswordReset emails a link the recipient can visit to choose a new
+// password.
+func (m *Mailer) SendPasswordReset(to, link string) error {
+	return m.send(to, "Reset your password", fmt.Sprintf("Click the link below to reset your password:\n\n%s\n\nIf you didn't request this, you can ignore this email.\n", link))
+}
+
+func (m *Mailer) send(to, subject, body string) error {
+	if m.cfg.Host == "" {
+		log.Printf("mail: SMTP not configured, would send to %s: %s\n%s", to, subject, body)
+		return nil
+	}
+
+	addr := fmt.Sprintf("%s:%s", m.cfg.Host, m.cfg.Port)
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\n\r\n%s\r\n", m.cfg.From, to, subject, body)
+
+	var auth smtp.Auth
+	if m.cfg.Username != "" {
+		auth = smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)
+	}
+
+	return smtp.SendMail(addr, auth, m.cfg.From, []string{to}, []byte(msg))
+}
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// MemoryStore is an in-memory SessionStore protected by a sync.RWMutex. It
+// does not persist anything on its own; pair it with SnapshotTo/LoadFrom to
+// survive a process restart.
+type MemoryStore struct {
+	mu       sync.RWMutex
+	sessions map[string]Session
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{sessions: make(map[string]Session)}
+}
+
+func (m *MemoryStore) Get(token string) (Session, bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	s, ok := m.sessions[token]
+	return s, ok, nil
+}
+
+func (m *MemoryStore) Put(s Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessions[s.Token] = s
+	return nil
+}
+
+func (m *MemoryStore) Delete(token string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, token)
+	return nil
+}
+
+func (m *MemoryStore) Sweep() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	for token, s := range m.sessions {
+		if s.Expiry.Before(now) {
+			delete(m.sessions, token)
+		}
+	}
+	return nil
+}
+
+// SnapshotTo writes every held session to w so it can be restored with
+// LoadFrom after a restart.
+func (m *MemoryStore) SnapshotTo(w io.Writer) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, s := range m.sessions {
+		if err := s.Serialize(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadFrom reloads sessions previously written by SnapshotTo, skipping any
+// that have already expired.
+func (m *MemoryStore) LoadFrom(r io.Reader) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	for {
+		s, err := Deserialize(r)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if s.Expiry.Before(now) {
+			continue
+		}
+		m.sessions[s.Token] = s
+	}
+}
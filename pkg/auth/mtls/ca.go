@@ -0,0 +1,157 @@
+package mtls
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"database/sql"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"time"
+)
+
+// CA is a minimal internal certificate authority used to enroll admin
+// client certificates without standing up an external PKI, the same way
+// crowdsec enrolls bouncers against its own local CA.
+type CA struct {
+	cert *x509.Certificate
+	key  *rsa.PrivateKey
+}
+
+// LoadOrCreateCA loads the CA key/cert from the ca table, generating and
+// persisting a new self-signed CA the first time it's called.
+func LoadOrCreateCA(db *sql.DB) (*CA, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS ca (
+		id       INTEGER PRIMARY KEY CHECK (id = 1),
+		key_pem  TEXT NOT NULL,
+		cert_pem TEXT NOT NULL
+	)`)
+	if err != nil {
+		return nil, err
+	}
+
+	var keyPEM, certPEM string
+	err = db.QueryRow("SELECT key_pem, cert_pem FROM ca WHERE id = 1").Scan(&keyPEM, &certPEM)
+	if err == sql.ErrNoRows {
+		return createCA(db)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return parseCA([]byte(keyPEM), []byte(certPEM))
+}
+
+func createCA(db *sql.DB) (*CA, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "nrv.dev-webapp internal CA"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().AddDate(10, 0, 0),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})
+
+	if _, err := db.Exec("INSERT INTO ca (id, key_pem, cert_pem) VALUES (1, ?, ?)", string(keyPEM), string(certPEM)); err != nil {
+		return nil, err
+	}
+
+	cert, err := x509.ParseCertificate(certDER)
+	if err != nil {
+		return nil, err
+	}
+	return &CA{cert: cert, key: key}, nil
+}
+
+func parseCA(keyPEM, certPEM []byte) (*CA, error) {
+	keyBlock, _ := pem.Decode(keyPEM)
+	if keyBlock == nil {
+		return nil, errors.New("mtls: malformed CA key")
+	}
+	key, err := x509.ParsePKCS1PrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	certBlock, _ := pem.Decode(certPEM)
+	if certBlock == nil {
+		return nil, errors.New("mtls: malformed CA certificate")
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CA{cert: cert, key: key}, nil
+}
+
+func randomSerial() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
+
+// CertPEM returns the CA's own certificate, PEM encoded, to be distributed
+// to the server as its -client-ca trust anchor.
+func (ca *CA) CertPEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw})
+}
+
+// SignCSR validates and signs a PEM-encoded certificate signing request,
+// returning a PEM-encoded client certificate valid for validity. The issued
+// certificate's Subject is taken verbatim from the CSR, so its CN and OU
+// are whatever the enrolling operator put there and can be checked later
+// by RequireClientCert.
+func (ca *CA) SignCSR(csrPEM []byte, validity time.Duration) ([]byte, error) {
+	block, _ := pem.Decode(csrPEM)
+	if block == nil {
+		return nil, errors.New("mtls: malformed CSR")
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, err
+	}
+
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      csr.Subject,
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(validity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	certDER, err := x509.CreateCertificate(rand.Reader, template, ca.cert, csr.PublicKey, ca.key)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}), nil
+}
@@ -0,0 +1,72 @@
+package mtls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"testing"
+)
+
+func requestWithCert(ou, cn string) *http.Request {
+	cert := &x509.Certificate{
+		Subject: pkix.Name{
+			OrganizationalUnit: []string{ou},
+			CommonName:         cn,
+		},
+	}
+	r := &http.Request{
+		TLS: &tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{cert},
+			VerifiedChains:   [][]*x509.Certificate{{cert}},
+		},
+	}
+	return r
+}
+
+func TestVerifyAcceptsMatchingOUAndCN(t *testing.T) {
+	r := requestWithCert("admin", "ops-laptop")
+	if !Verify(r, "admin", []string{"ops-laptop"}) {
+		t.Fatal("Verify rejected a cert with a matching OU and CN")
+	}
+}
+
+func TestVerifyRejectsWrongOU(t *testing.T) {
+	r := requestWithCert("engineering", "ops-laptop")
+	if Verify(r, "admin", []string{"ops-laptop"}) {
+		t.Fatal("Verify accepted a cert whose OU doesn't match the required OU")
+	}
+}
+
+func TestVerifyRejectsCNNotAllowed(t *testing.T) {
+	r := requestWithCert("admin", "someone-elses-laptop")
+	if Verify(r, "admin", []string{"ops-laptop"}) {
+		t.Fatal("Verify accepted a cert with the right OU but an unlisted CN")
+	}
+}
+
+func TestVerifyRejectsNoTLS(t *testing.T) {
+	r := &http.Request{}
+	if Verify(r, "admin", []string{"ops-laptop"}) {
+		t.Fatal("Verify accepted a plain HTTP request with no TLS connection")
+	}
+}
+
+func TestVerifyRejectsUnverifiedChain(t *testing.T) {
+	cert := &x509.Certificate{
+		Subject: pkix.Name{
+			OrganizationalUnit: []string{"admin"},
+			CommonName:         "ops-laptop",
+		},
+	}
+	r := &http.Request{
+		TLS: &tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{cert},
+			// VerifiedChains left empty, as if the client cert didn't chain
+			// to a trusted CA.
+		},
+	}
+	if Verify(r, "admin", []string{"ops-laptop"}) {
+		t.Fatal("Verify accepted a request with no verified chain")
+	}
+}
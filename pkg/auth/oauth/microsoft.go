@@ -0,0 +1,228 @@
+package oauth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/microsoft"
+)
+
+// MicrosoftProvider signs in with a Microsoft account via the Microsoft
+// Graph "me" endpoint. It also exposes FetchMinecraftProfile, which walks
+// the separate Xbox Live -> XSTS -> Minecraft services token-exchange
+// chain, for a future community feature that gates access on Minecraft
+// ownership; that chain is never invoked as part of basic sign-in.
+type MicrosoftProvider struct {
+	config *oauth2.Config
+}
+
+// NewMicrosoftProvider builds a MicrosoftProvider from client credentials
+// sourced from env vars or a config file, never from flags.
+func NewMicrosoftProvider(clientID, clientSecret, redirectURL string) *MicrosoftProvider {
+	return &MicrosoftProvider{
+		config: &oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			RedirectURL:  redirectURL,
+			Scopes:       []string{"openid", "profile", "email", "offline_access", "User.Read"},
+			Endpoint:     microsoft.LiveConnectEndpoint,
+		},
+	}
+}
+
+func (p *MicrosoftProvider) Name() string { return "microsoft" }
+
+func (p *MicrosoftProvider) AuthURL(state string) string {
+	return p.config.AuthCodeURL(state)
+}
+
+func (p *MicrosoftProvider) Exchange(ctx context.Context, code string) (*oauth2.Token, error) {
+	return p.config.Exchange(ctx, code)
+}
+
+// graphUser is the subset of the Microsoft Graph /v1.0/me response we need.
+type graphUser struct {
+	ID                string `json:"id"`
+	DisplayName       string `json:"displayName"`
+	Mail              string `json:"mail"`
+	UserPrincipalName string `json:"userPrincipalName"`
+}
+
+// FetchProfile resolves the signed-in Microsoft account via the Graph "me"
+// endpoint. id is the account's stable Graph object id, so it's used as
+// Profile.Subject regardless of whether the account owns Minecraft.
+func (p *MicrosoftProvider) FetchProfile(ctx context.Context, token *oauth2.Token) (Profile, error) {
+	client := p.config.Client(ctx, token)
+	resp, err := client.Get("https://graph.microsoft.com/v1.0/me")
+	if err != nil {
+		return Profile{}, fmt.Errorf("microsoft: graph me: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Profile{}, fmt.Errorf("microsoft: unexpected status fetching profile: %s", resp.Status)
+	}
+
+	var u graphUser
+	if err := json.NewDecoder(resp.Body).Decode(&u); err != nil {
+		return Profile{}, err
+	}
+
+	email := u.Mail
+	if email == "" {
+		email = u.UserPrincipalName
+	}
+
+	return Profile{
+		Subject:  u.ID,
+		Username: u.DisplayName,
+		Email:    email,
+	}, nil
+}
+
+type xblAuthRequest struct {
+	Properties   xblProperties `json:"Properties"`
+	RelyingParty string        `json:"RelyingParty"`
+	TokenType    string        `json:"TokenType"`
+}
+
+type xblProperties struct {
+	AuthMethod string `json:"AuthMethod"`
+	SiteName   string `json:"SiteName"`
+	RpsTicket  string `json:"RpsTicket"`
+}
+
+type xstsAuthRequest struct {
+	Properties   xstsProperties `json:"Properties"`
+	RelyingParty string         `json:"RelyingParty"`
+	TokenType    string         `json:"TokenType"`
+}
+
+type xstsProperties struct {
+	SandboxID  string   `json:"SandboxId"`
+	UserTokens []string `json:"UserTokens"`
+}
+
+// xTokenResponse is shared by the Xbox Live and XSTS auth responses; both
+// return a Token plus the user hash under DisplayClaims.xui.
+type xTokenResponse struct {
+	Token         string `json:"Token"`
+	DisplayClaims struct {
+		Xui []struct {
+			Uhs string `json:"uhs"`
+		} `json:"xui"`
+	} `json:"DisplayClaims"`
+}
+
+type minecraftLoginResponse struct {
+	AccessToken string `json:"access_token"`
+}
+
+// MinecraftProfile is the Minecraft profile linked to a Microsoft account,
+// resolved by FetchMinecraftProfile.
+type MinecraftProfile struct {
+	ID   string
+	Name string
+}
+
+func postJSON(ctx context.Context, url string, body, out interface{}) error {
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(buf))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s from %s", resp.Status, url)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// FetchMinecraftProfile exchanges the Microsoft access token for an Xbox
+// Live token, then an XSTS token, then the Minecraft profile linked to that
+// XSTS identity. It's used to gate the (not yet built) Minecraft-linked
+// community feature and must never be called as part of basic sign-in,
+// since most Microsoft accounts don't own Minecraft.
+func (p *MicrosoftProvider) FetchMinecraftProfile(ctx context.Context, token *oauth2.Token) (MinecraftProfile, error) {
+	var xbl xTokenResponse
+	err := postJSON(ctx, "https://user.auth.xboxlive.com/user/authenticate", xblAuthRequest{
+		Properties: xblProperties{
+			AuthMethod: "RPS",
+			SiteName:   "user.auth.xboxlive.com",
+			RpsTicket:  "d=" + token.AccessToken,
+		},
+		RelyingParty: "http://auth.xboxlive.com",
+		TokenType:    "JWT",
+	}, &xbl)
+	if err != nil {
+		return MinecraftProfile{}, fmt.Errorf("microsoft: xbox live auth: %w", err)
+	}
+
+	var xsts xTokenResponse
+	err = postJSON(ctx, "https://xsts.auth.xboxlive.com/xsts/authorize", xstsAuthRequest{
+		Properties: xstsProperties{
+			SandboxID:  "RETAIL",
+			UserTokens: []string{xbl.Token},
+		},
+		RelyingParty: "rp://api.minecraftservices.com/",
+		TokenType:    "JWT",
+	}, &xsts)
+	if err != nil {
+		return MinecraftProfile{}, fmt.Errorf("microsoft: xsts auth: %w", err)
+	}
+	if len(xsts.DisplayClaims.Xui) == 0 {
+		return MinecraftProfile{}, fmt.Errorf("microsoft: xsts response missing user hash")
+	}
+	uhs := xsts.DisplayClaims.Xui[0].Uhs
+
+	var mc minecraftLoginResponse
+	err = postJSON(ctx, "https://api.minecraftservices.com/authentication/login_with_xbox", map[string]string{
+		"identityToken": fmt.Sprintf("XBL3.0 x=%s;%s", uhs, xsts.Token),
+	}, &mc)
+	if err != nil {
+		return MinecraftProfile{}, fmt.Errorf("microsoft: minecraft login: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.minecraftservices.com/minecraft/profile", nil)
+	if err != nil {
+		return MinecraftProfile{}, err
+	}
+	req.Header.Set("Authorization", "Bearer "+mc.AccessToken)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return MinecraftProfile{}, fmt.Errorf("microsoft: minecraft profile: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return MinecraftProfile{}, fmt.Errorf("microsoft: account does not own Minecraft")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return MinecraftProfile{}, fmt.Errorf("microsoft: unexpected status fetching minecraft profile: %s", resp.Status)
+	}
+
+	var profile minecraftProfileResponse
+	if err := json.NewDecoder(resp.Body).Decode(&profile); err != nil {
+		return MinecraftProfile{}, err
+	}
+
+	return MinecraftProfile{ID: profile.ID, Name: profile.Name}, nil
+}
+
+type minecraftProfileResponse struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
@@ -0,0 +1,35 @@
+// Package oauth provides a pluggable OAuth2 login path that sits alongside
+// the invite-code signup flow: a Provider interface plus GitHub and
+// Microsoft implementations.
+package oauth
+
+import (
+	"context"
+
+	"golang.org/x/oauth2"
+)
+
+// Profile is the identity information a Provider resolves after exchanging
+// an authorization code for a token.
+type Profile struct {
+	// Subject is the provider's stable, unique identifier for the account
+	// (never reused, unlike a username or email).
+	Subject  string
+	Username string
+	Email    string
+}
+
+// Provider is a single OAuth2 identity provider, wired up behind
+// /api/oauth/{provider}/start and /api/oauth/{provider}/callback.
+type Provider interface {
+	// Name identifies the provider in URLs and the users.oauth_provider column.
+	Name() string
+	// AuthURL returns the URL to redirect the user to in order to start the
+	// provider's consent flow, embedding state for CSRF protection.
+	AuthURL(state string) string
+	// Exchange trades an authorization code for a token.
+	Exchange(ctx context.Context, code string) (*oauth2.Token, error)
+	// FetchProfile resolves the signed-in user's profile using the token
+	// returned by Exchange.
+	FetchProfile(ctx context.Context, token *oauth2.Token) (Profile, error)
+}
@@ -0,0 +1,111 @@
+// Package auth provides the session layer shared by the HTTP handlers and the
+// CLI: a SessionStore interface plus in-memory and SQLite-backed
+// implementations.
+package auth
+
+import (
+	"encoding/binary"
+	"io"
+	"time"
+)
+
+// Session is a single authenticated session tied to a user.
+type Session struct {
+	Token    string
+	Username string
+	Expiry   time.Time
+	Created  time.Time
+	LastSeen time.Time
+}
+
+// IsExpired reports whether the session has passed its expiry time.
+func (s Session) IsExpired() bool {
+	return s.Expiry.Before(time.Now())
+}
+
+func writeField(w io.Writer, b []byte) error {
+	if err := binary.Write(w, binary.BigEndian, uint32(len(b))); err != nil {
+		return err
+	}
+	_, err := w.Write(b)
+	return err
+}
+
+func readField(r io.Reader) ([]byte, error) {
+	var n uint32
+	if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+		return nil, err
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func writeTime(w io.Writer, t time.Time) error {
+	b, err := t.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return writeField(w, b)
+}
+
+func readTime(r io.Reader) (time.Time, error) {
+	var t time.Time
+	b, err := readField(r)
+	if err != nil {
+		return t, err
+	}
+	err = t.UnmarshalBinary(b)
+	return t, err
+}
+
+// Serialize writes the session as a sequence of length-prefixed fields (a
+// uint32 length followed by the raw bytes), so a store can snapshot its
+// sessions to disk on shutdown and reload them on startup.
+func (s Session) Serialize(w io.Writer) error {
+	if err := writeField(w, []byte(s.Token)); err != nil {
+		return err
+	}
+	if err := writeField(w, []byte(s.Username)); err != nil {
+		return err
+	}
+	if err := writeTime(w, s.Expiry); err != nil {
+		return err
+	}
+	if err := writeTime(w, s.Created); err != nil {
+		return err
+	}
+	return writeTime(w, s.LastSeen)
+}
+
+// Deserialize reads a single session previously written by Serialize. It
+// returns io.EOF when r has no more sessions to read.
+func Deserialize(r io.Reader) (Session, error) {
+	var s Session
+
+	token, err := readField(r)
+	if err != nil {
+		return s, err
+	}
+	s.Token = string(token)
+
+	username, err := readField(r)
+	if err != nil {
+		return s, err
+	}
+	s.Username = string(username)
+
+	if s.Expiry, err = readTime(r); err != nil {
+		return s, err
+	}
+	if s.Created, err = readTime(r); err != nil {
+		return s, err
+	}
+	if s.LastSeen, err = readTime(r); err != nil {
+		return s, err
+	}
+
+	return s, nil
+}
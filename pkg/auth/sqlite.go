@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"database/sql"
+	"time"
+)
+
+// SQLiteStore is a SessionStore backed by a "sessions" table, so sessions
+// survive a server restart.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore creates the sessions table if it doesn't already exist and
+// returns a store backed by it.
+func NewSQLiteStore(db *sql.DB) (*SQLiteStore, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS sessions (
+		token      TEXT PRIMARY KEY,
+		username   TEXT NOT NULL,
+		expiry     DATETIME NOT NULL,
+		created_at DATETIME NOT NULL,
+		last_seen  DATETIME NOT NULL
+	)`)
+	if err != nil {
+		return nil, err
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+func (s *SQLiteStore) Get(token string) (Session, bool, error) {
+	sess := Session{Token: token}
+	row := s.db.QueryRow("SELECT username, expiry, created_at, last_seen FROM sessions WHERE token = ?", token)
+	err := row.Scan(&sess.Username, &sess.Expiry, &sess.Created, &sess.LastSeen)
+	if err == sql.ErrNoRows {
+		return Session{}, false, nil
+	}
+	if err != nil {
+		return Session{}, false, err
+	}
+	return sess, true, nil
+}
+
+func (s *SQLiteStore) Put(sess Session) error {
+	_, err := s.db.Exec(`INSERT INTO sessions (token, username, expiry, created_at, last_seen)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(token) DO UPDATE SET
+			username=excluded.username,
+			expiry=excluded.expiry,
+			created_at=excluded.created_at,
+			last_seen=excluded.last_seen`,
+		sess.Token, sess.Username, sess.Expiry, sess.Created, sess.LastSeen)
+	return err
+}
+
+func (s *SQLiteStore) Delete(token string) error {
+	_, err := s.db.Exec("DELETE FROM sessions WHERE token = ?", token)
+	return err
+}
+
+func (s *SQLiteStore) Sweep() error {
+	_, err := s.db.Exec("DELETE FROM sessions WHERE expiry < ?", time.Now())
+	return err
+}
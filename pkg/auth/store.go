@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"log"
+	"time"
+)
+
+// SessionStore is the session backend used by the signin, logout, and
+// checkAuth handlers. Implementations must be safe for concurrent use.
+type SessionStore interface {
+	// Get looks up a session by token. The second return value is false if
+	// no session exists for that token.
+	Get(token string) (Session, bool, error)
+	// Put inserts or updates a session.
+	Put(s Session) error
+	// Delete removes a session, if present.
+	Delete(token string) error
+	// Sweep removes all expired sessions.
+	Sweep() error
+}
+
+// RunSweeper calls store.Sweep() at the given interval until stop is closed.
+// It is meant to be run in its own goroutine.
+func RunSweeper(store SessionStore, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := store.Sweep(); err != nil {
+				log.Printf("auth: session sweep failed: %v", err)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
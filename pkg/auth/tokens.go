@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// TokenStore persists single-use tokens (email verification, password
+// reset), keyed by purpose. Only a token's hash is ever stored.
+type TokenStore struct {
+	db *sql.DB
+}
+
+// NewTokenStore creates the auth_tokens table if it doesn't already exist
+// and returns a store backed by it.
+func NewTokenStore(db *sql.DB) (*TokenStore, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS auth_tokens (
+		token_hash TEXT PRIMARY KEY,
+		purpose    TEXT NOT NULL,
+		username   TEXT NOT NULL,
+		expiry     DATETIME NOT NULL,
+		consumed   BOOLEAN NOT NULL DEFAULT 0
+	)`)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenStore{db: db}, nil
+}
+
+func hashAuthToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Issue generates a new single-use token for username under purpose (e.g.
+// "verify" or "reset") and returns its raw value. The raw value is only
+// ever available here; the store keeps just its hash.
+func (s *TokenStore) Issue(purpose, username string, ttl time.Duration) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+
+	_, err := s.db.Exec("INSERT INTO auth_tokens (token_hash, purpose, username, expiry, consumed) VALUES (?, ?, ?, ?, 0)",
+		hashAuthToken(token), purpose, username, time.Now().Add(ttl))
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Consume validates token against purpose and, if it is unconsumed and
+// unexpired, marks it consumed and calls fn with the username it was
+// issued to, all inside one transaction. If fn returns an error the whole
+// transaction (including the consumed flag) is rolled back, so a token
+// can't be silently burned by a failed state change, and a token can never
+// be replayed once fn succeeds.
+func (s *TokenStore) Consume(purpose, token string, fn func(tx *sql.Tx, username string) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	tokenHash := hashAuthToken(token)
+
+	var username string
+	var expiry time.Time
+	var consumed bool
+	row := tx.QueryRow("SELECT username, expiry, consumed FROM auth_tokens WHERE token_hash = ? AND purpose = ?", tokenHash, purpose)
+	if err := row.Scan(&username, &expiry, &consumed); err != nil {
+		return err
+	}
+	if consumed {
+		return errors.New("auth: token already used")
+	}
+	if expiry.Before(time.Now()) {
+		return errors.New("auth: token expired")
+	}
+
+	if _, err := tx.Exec("UPDATE auth_tokens SET consumed = 1 WHERE token_hash = ?", tokenHash); err != nil {
+		return err
+	}
+
+	if err := fn(tx, username); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func newTestTokenStore(t *testing.T) *TokenStore {
+	t.Helper()
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	store, err := NewTokenStore(db)
+	if err != nil {
+		t.Fatalf("NewTokenStore: %v", err)
+	}
+	return store
+}
+
+func TestTokenStoreConsumeRoundTrip(t *testing.T) {
+	store := newTestTokenStore(t)
+
+	token, err := store.Issue("verify", "alice", time.Hour)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	var gotUsername string
+	err = store.Consume("verify", token, func(tx *sql.Tx, username string) error {
+		gotUsername = username
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Consume: %v", err)
+	}
+	if gotUsername != "alice" {
+		t.Errorf("Consume username = %q, want alice", gotUsername)
+	}
+}
+
+// TestTokenStoreConsumeRejectsReplay is the core single-use guarantee: once
+// a token has been successfully consumed, presenting it again must fail.
+func TestTokenStoreConsumeRejectsReplay(t *testing.T) {
+	store := newTestTokenStore(t)
+
+	token, err := store.Issue("verify", "alice", time.Hour)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	noop := func(tx *sql.Tx, username string) error { return nil }
+	if err := store.Consume("verify", token, noop); err != nil {
+		t.Fatalf("first Consume: %v", err)
+	}
+
+	if err := store.Consume("verify", token, noop); err == nil {
+		t.Fatal("second Consume of the same token succeeded, want error")
+	}
+}
+
+// TestTokenStoreConsumeRollsBackOnFnError checks that a token isn't burned
+// when the caller's state change fails, so the user gets to retry with the
+// same link instead of the token being silently wasted.
+func TestTokenStoreConsumeRollsBackOnFnError(t *testing.T) {
+	store := newTestTokenStore(t)
+
+	token, err := store.Issue("reset", "alice", time.Hour)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	fnErr := errors.New("boom")
+	err = store.Consume("reset", token, func(tx *sql.Tx, username string) error {
+		return fnErr
+	})
+	if !errors.Is(err, fnErr) {
+		t.Fatalf("Consume error = %v, want %v", err, fnErr)
+	}
+
+	var calledAgain bool
+	err = store.Consume("reset", token, func(tx *sql.Tx, username string) error {
+		calledAgain = true
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Consume after rollback: %v", err)
+	}
+	if !calledAgain {
+		t.Fatal("token was consumed on the failed attempt despite the rollback")
+	}
+}
+
+func TestTokenStoreConsumeRejectsWrongPurpose(t *testing.T) {
+	store := newTestTokenStore(t)
+
+	token, err := store.Issue("verify", "alice", time.Hour)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	err = store.Consume("reset", token, func(tx *sql.Tx, username string) error { return nil })
+	if err == nil {
+		t.Fatal("Consume accepted a token issued for a different purpose")
+	}
+}
+
+func TestTokenStoreConsumeRejectsExpiredToken(t *testing.T) {
+	store := newTestTokenStore(t)
+
+	token, err := store.Issue("verify", "alice", -time.Minute)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	err = store.Consume("verify", token, func(tx *sql.Tx, username string) error { return nil })
+	if err == nil {
+		t.Fatal("Consume accepted an expired token")
+	}
+}